@@ -1,29 +1,62 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// NightscoutTarget maps a single LibreLinkUp patient connection to a
+// Nightscout instance. A connection is matched by PatientID first, falling
+// back to FirstName+LastName when PatientID is not known ahead of time.
+type NightscoutTarget struct {
+	PatientID     string `json:"patient_id,omitempty"`
+	FirstName     string `json:"first_name,omitempty"`
+	LastName      string `json:"last_name,omitempty"`
+	NightscoutURL string `json:"nightscout_url"`
+	APISecret     string `json:"api_secret"`
+	DeviceSuffix  string `json:"device_suffix,omitempty"`
+}
+
 // Config holds all application configuration
 type Config struct {
-	LinkUpUsername     string
-	LinkUpPassword     string
-	LinkUpRegion       string
-	LinkUpTimeInterval int
-	NightscoutURL      string
-	NightscoutAPIToken string // This is the API_SECRET, not a hashed token
+	LinkUpUsername      string
+	LinkUpPassword      string
+	LinkUpRegion        string
+	LinkUpTimeInterval  int
+	NightscoutURL       string
+	NightscoutAPIToken  string // This is the API_SECRET, not a hashed token
+	NightscoutBufferDir string
+	// NightscoutTargets maps specific patients to their own Nightscout
+	// instance, for caregivers watching multiple LibreLinkUp shares. When
+	// empty, every connection is posted to NightscoutURL/NightscoutAPIToken.
+	NightscoutTargets []NightscoutTarget
+	LogLevel          string // debug, info, warn, or error
+	LogFormat         string // text or json
+	// HTTPRetryTimeout is the total wall-clock budget each LibreLink Up or
+	// Nightscout call gets across all retry attempts before giving up.
+	HTTPRetryTimeout time.Duration
+	// HTTPListenAddr is the address the /healthz, /readyz, /status, and
+	// /metrics server listens on. Leave empty to disable the server.
+	HTTPListenAddr string
 }
 
 // LoadConfig loads configuration from environment variables
 func LoadConfig() (*Config, error) {
 	cfg := &Config{
-		LinkUpUsername:     getEnvOrDefault("LINK_UP_USERNAME", ""),
-		LinkUpPassword:     getEnvOrDefault("LINK_UP_PASSWORD", ""),
-		LinkUpRegion:       getEnvOrDefault("LINK_UP_REGION", "EU"),
-		NightscoutURL:      getEnvOrDefault("NIGHTSCOUT_URL", ""),
-		NightscoutAPIToken: getEnvOrDefault("NIGHTSCOUT_API_TOKEN", ""),
+		LinkUpUsername:      getEnvOrDefault("LINK_UP_USERNAME", ""),
+		LinkUpPassword:      getEnvOrDefault("LINK_UP_PASSWORD", ""),
+		LinkUpRegion:        getEnvOrDefault("LINK_UP_REGION", "EU"),
+		NightscoutURL:       getEnvOrDefault("NIGHTSCOUT_URL", ""),
+		NightscoutAPIToken:  getEnvOrDefault("NIGHTSCOUT_API_TOKEN", ""),
+		NightscoutBufferDir: getEnvOrDefault("NIGHTSCOUT_BUFFER_DIR", "data/nightscout-wal"),
+		LogLevel:            getEnvOrDefault("LOG_LEVEL", "info"),
+		LogFormat:           getEnvOrDefault("LOG_FORMAT", "text"),
+		HTTPListenAddr:      getEnvOrDefault("HTTP_LISTEN_ADDR", ":8080"),
 	}
 
 	// Parse interval with default
@@ -34,6 +67,24 @@ func LoadConfig() (*Config, error) {
 	}
 	cfg.LinkUpTimeInterval = interval
 
+	retryTimeoutStr := getEnvOrDefault("HTTP_RETRY_TIMEOUT", "30s")
+	retryTimeout, err := time.ParseDuration(retryTimeoutStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTTP_RETRY_TIMEOUT: %v", err)
+	}
+	cfg.HTTPRetryTimeout = retryTimeout
+
+	// NIGHTSCOUT_TARGETS holds a JSON array of NightscoutTarget for
+	// multi-patient setups, e.g.:
+	//   [{"patient_id":"abc123","nightscout_url":"ns1.example.com","api_secret":"...","device_suffix":"kid"}]
+	if targetsJSON := getEnvOrDefault("NIGHTSCOUT_TARGETS", ""); targetsJSON != "" {
+		var targets []NightscoutTarget
+		if err := json.Unmarshal([]byte(targetsJSON), &targets); err != nil {
+			return nil, fmt.Errorf("invalid NIGHTSCOUT_TARGETS: %v", err)
+		}
+		cfg.NightscoutTargets = targets
+	}
+
 	return cfg, nil
 }
 
@@ -45,18 +96,81 @@ func (c *Config) Validate() error {
 	if c.LinkUpPassword == "" {
 		return fmt.Errorf("LINK_UP_PASSWORD is required")
 	}
-	if c.NightscoutURL == "" {
-		return fmt.Errorf("NIGHTSCOUT_URL is required")
-	}
-	if c.NightscoutAPIToken == "" {
-		return fmt.Errorf("NIGHTSCOUT_API_TOKEN is required")
+	if len(c.NightscoutTargets) == 0 {
+		if c.NightscoutURL == "" {
+			return fmt.Errorf("NIGHTSCOUT_URL is required when NIGHTSCOUT_TARGETS is not set")
+		}
+		if c.NightscoutAPIToken == "" {
+			return fmt.Errorf("NIGHTSCOUT_API_TOKEN is required when NIGHTSCOUT_TARGETS is not set")
+		}
 	}
 	if c.LinkUpTimeInterval < 1 {
 		return fmt.Errorf("LINK_UP_TIME_INTERVAL must be at least 1 minute")
 	}
+	if c.NightscoutBufferDir == "" {
+		return fmt.Errorf("NIGHTSCOUT_BUFFER_DIR must not be empty")
+	}
+	if c.HTTPRetryTimeout <= 0 {
+		return fmt.Errorf("HTTP_RETRY_TIMEOUT must be positive")
+	}
+	if c.HTTPRetryTimeout >= time.Duration(c.LinkUpTimeInterval)*time.Minute {
+		return fmt.Errorf("HTTP_RETRY_TIMEOUT must be less than LINK_UP_TIME_INTERVAL")
+	}
+	for i, target := range c.NightscoutTargets {
+		if target.PatientID == "" && target.FirstName == "" && target.LastName == "" {
+			return fmt.Errorf("NIGHTSCOUT_TARGETS[%d] must set patient_id or first_name/last_name", i)
+		}
+		if target.NightscoutURL == "" {
+			return fmt.Errorf("NIGHTSCOUT_TARGETS[%d] must set nightscout_url", i)
+		}
+		if target.APISecret == "" {
+			return fmt.Errorf("NIGHTSCOUT_TARGETS[%d] must set api_secret", i)
+		}
+	}
+	switch strings.ToLower(c.LogLevel) {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("LOG_LEVEL must be one of debug, info, warn, error, got %q", c.LogLevel)
+	}
+	switch strings.ToLower(c.LogFormat) {
+	case "text", "json":
+	default:
+		return fmt.Errorf("LOG_FORMAT must be one of text, json, got %q", c.LogFormat)
+	}
+	if c.HTTPListenAddr != "" {
+		if _, _, err := net.SplitHostPort(c.HTTPListenAddr); err != nil {
+			return fmt.Errorf("invalid HTTP_LISTEN_ADDR: %v", err)
+		}
+	}
 	return nil
 }
 
+// ResolveNightscoutTarget finds the Nightscout target configured for a
+// connection, matching by patient ID first and then by first+last name. When
+// no explicit targets are configured, every connection routes to the default
+// NightscoutURL/NightscoutAPIToken with no device suffix.
+func (c *Config) ResolveNightscoutTarget(patientID, firstName, lastName string) (NightscoutTarget, error) {
+	if len(c.NightscoutTargets) == 0 {
+		return NightscoutTarget{
+			NightscoutURL: c.NightscoutURL,
+			APISecret:     c.NightscoutAPIToken,
+		}, nil
+	}
+
+	for _, target := range c.NightscoutTargets {
+		if target.PatientID != "" && target.PatientID == patientID {
+			return target, nil
+		}
+	}
+	for _, target := range c.NightscoutTargets {
+		if target.PatientID == "" && target.FirstName == firstName && target.LastName == lastName {
+			return target, nil
+		}
+	}
+
+	return NightscoutTarget{}, fmt.Errorf("no Nightscout target configured for patient %q (%s %s)", patientID, firstName, lastName)
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// optionalEnvVars lists every env var LoadConfig reads that has a default,
+// so TestLoadConfigDefaultsValidate can start from a clean slate regardless
+// of what's set in the test environment.
+var optionalEnvVars = []string{
+	"LINK_UP_REGION", "LINK_UP_TIME_INTERVAL", "NIGHTSCOUT_BUFFER_DIR",
+	"NIGHTSCOUT_TARGETS", "LOG_LEVEL", "LOG_FORMAT", "HTTP_RETRY_TIMEOUT",
+	"HTTP_LISTEN_ADDR",
+}
+
+// TestLoadConfigDefaultsValidate ensures a deployment that only sets the
+// required env vars loads and validates cleanly, i.e. the defaults for
+// HTTP_RETRY_TIMEOUT and LINK_UP_TIME_INTERVAL don't conflict with each
+// other out of the box.
+func TestLoadConfigDefaultsValidate(t *testing.T) {
+	for _, key := range optionalEnvVars {
+		os.Unsetenv(key)
+	}
+
+	t.Setenv("LINK_UP_USERNAME", "user@example.com")
+	t.Setenv("LINK_UP_PASSWORD", "password")
+	t.Setenv("NIGHTSCOUT_URL", "https://nightscout.example.com")
+	t.Setenv("NIGHTSCOUT_API_TOKEN", "secret")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() failed with only required env vars set: %v", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() failed with default optional config: %v", err)
+	}
+}
+
+// TestValidateAllowsTargetsOnlyWithoutGlobalFallback ensures an operator who
+// only wants per-patient Nightscout targets doesn't also have to configure a
+// bogus, unused global NightscoutURL/NightscoutAPIToken just to pass
+// validation.
+func TestValidateAllowsTargetsOnlyWithoutGlobalFallback(t *testing.T) {
+	cfg := &Config{
+		LinkUpUsername:      "user@example.com",
+		LinkUpPassword:      "password",
+		LinkUpTimeInterval:  1,
+		NightscoutBufferDir: "data/nightscout-wal",
+		LogLevel:            "info",
+		LogFormat:           "text",
+		HTTPRetryTimeout:    30 * time.Second,
+		NightscoutTargets: []NightscoutTarget{
+			{PatientID: "abc123", NightscoutURL: "https://ns.example.com", APISecret: "secret"},
+		},
+	}
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() = %v, want nil when every patient is covered by NightscoutTargets", err)
+	}
+}
+
+// TestValidateRequiresGlobalFallbackWithoutTargets ensures the global
+// NightscoutURL/NightscoutAPIToken are still required when no per-patient
+// targets are configured at all.
+func TestValidateRequiresGlobalFallbackWithoutTargets(t *testing.T) {
+	cfg := &Config{
+		LinkUpUsername:      "user@example.com",
+		LinkUpPassword:      "password",
+		LinkUpTimeInterval:  1,
+		NightscoutBufferDir: "data/nightscout-wal",
+		LogLevel:            "info",
+		LogFormat:           "text",
+		HTTPRetryTimeout:    30 * time.Second,
+	}
+
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("Validate() = nil, want an error when neither NightscoutURL nor NightscoutTargets is set")
+	}
+}
@@ -0,0 +1,103 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// NewMux builds the connector's HTTP surface: /healthz, /readyz, /status,
+// and /metrics, all reading from status.
+func NewMux(status *Status) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", status.handleHealthz)
+	mux.HandleFunc("/readyz", status.handleReadyz)
+	mux.HandleFunc("/status", status.handleStatus)
+	mux.HandleFunc("/metrics", status.handleMetrics)
+	return mux
+}
+
+// handleHealthz reports 200 for as long as the process is running, so an
+// orchestrator can tell a wedged process from a crashed one.
+func (s *Status) handleHealthz(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleReadyz reports 200 only once the first successful sync has posted a
+// reading to Nightscout, and only while the freshest successful post is
+// younger than s.readyMaxAge. Gating on the post rather than the fetched
+// reading means a patient whose LibreLink fetch keeps succeeding but whose
+// Nightscout posts are all failing (piling up in the write-ahead buffer)
+// correctly goes unready instead of reporting healthy forever.
+func (s *Status) handleReadyz(w http.ResponseWriter, _ *http.Request) {
+	s.mu.RLock()
+	age, ok := s.freshestPostAge()
+	s.mu.RUnlock()
+
+	if !ok || age > s.readyMaxAge {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+type statusResponse struct {
+	LastLoginAt  time.Time                `json:"lastLoginAt,omitempty"`
+	LastLoginErr string                   `json:"lastLoginError,omitempty"`
+	Patients     map[string]PatientStatus `json:"patients"`
+}
+
+// handleStatus reports last login time, the latest reading, last successful
+// Nightscout post, and the last error seen, per patient.
+func (s *Status) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	s.mu.RLock()
+	resp := statusResponse{
+		LastLoginAt:  s.lastLoginAt,
+		LastLoginErr: s.lastLoginErr,
+		Patients:     make(map[string]PatientStatus, len(s.patients)),
+	}
+	for slug, p := range s.patients {
+		resp.Patients[slug] = *p
+	}
+	s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// handleMetrics reports the connector's counters and gauges in Prometheus
+// text exposition format.
+func (s *Status) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	s.mu.RLock()
+	loginTotal := s.loginTotal
+	loginFailures := s.loginFailures
+	postsTotal := s.postsTotal
+	postFailures := s.postFailures
+	syncDuration := s.lastSyncDuration
+	age, hasReading := s.freshestReadingAge()
+	s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	writeCounter(w, "librelink_login_total", "Total LibreLink Up login attempts.", loginTotal)
+	writeCounter(w, "librelink_login_failures_total", "LibreLink Up login attempts that failed.", loginFailures)
+	writeCounter(w, "nightscout_posts_total", "Total Nightscout entry POSTs attempted.", postsTotal)
+	writeCounter(w, "nightscout_post_failures_total", "Nightscout entry POSTs that failed.", postFailures)
+
+	fmt.Fprintln(w, "# HELP sync_duration_seconds Duration of the most recent sync tick in seconds.")
+	fmt.Fprintln(w, "# TYPE sync_duration_seconds gauge")
+	fmt.Fprintf(w, "sync_duration_seconds %g\n", syncDuration.Seconds())
+
+	fmt.Fprintln(w, "# HELP glucose_reading_age_seconds Age of the most recent glucose reading across all patients, in seconds.")
+	fmt.Fprintln(w, "# TYPE glucose_reading_age_seconds gauge")
+	if hasReading {
+		fmt.Fprintf(w, "glucose_reading_age_seconds %g\n", age.Seconds())
+	}
+}
+
+func writeCounter(w http.ResponseWriter, name, help string, value uint64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, value)
+}
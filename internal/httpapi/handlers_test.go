@@ -0,0 +1,54 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func getReadyz(t *testing.T, s *Status) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	rec := httptest.NewRecorder()
+	s.handleReadyz(rec, req)
+	return rec.Code
+}
+
+func TestHandleReadyzNotReadyBeforeFirstPost(t *testing.T) {
+	s := NewStatus(time.Minute)
+
+	if code := getReadyz(t, s); code != http.StatusServiceUnavailable {
+		t.Fatalf("readyz before any post = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestHandleReadyzReadyAfterSuccessfulPost(t *testing.T) {
+	s := NewStatus(time.Minute)
+	s.RecordPost("patient", nil)
+
+	if code := getReadyz(t, s); code != http.StatusOK {
+		t.Fatalf("readyz after a successful post = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestHandleReadyzNotReadyWhenFetchSucceedsButPostsKeepFailing(t *testing.T) {
+	s := NewStatus(time.Minute)
+	s.RecordReading("patient", Reading{Value: 100, Timestamp: time.Now()})
+	s.RecordPost("patient", errors.New("nightscout unreachable"))
+
+	if code := getReadyz(t, s); code != http.StatusServiceUnavailable {
+		t.Fatalf("readyz with fresh reading but only failed posts = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestHandleReadyzNotReadyOncePostGoesStale(t *testing.T) {
+	s := NewStatus(time.Millisecond)
+	s.RecordPost("patient", nil)
+	time.Sleep(5 * time.Millisecond)
+
+	if code := getReadyz(t, s); code != http.StatusServiceUnavailable {
+		t.Fatalf("readyz after the last successful post goes stale = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+}
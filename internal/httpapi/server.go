@@ -0,0 +1,38 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Server serves the connector's health, readiness, status, and metrics
+// endpoints.
+type Server struct {
+	httpServer *http.Server
+}
+
+// NewServer creates a Server listening on addr. Start it with Serve and stop
+// it with Shutdown.
+func NewServer(addr string, status *Status) *Server {
+	return &Server{
+		httpServer: &http.Server{
+			Addr:    addr,
+			Handler: NewMux(status),
+		},
+	}
+}
+
+// Serve blocks serving HTTP until the server is shut down, returning nil in
+// that case instead of http.ErrServerClosed.
+func (s *Server) Serve() error {
+	if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown gracefully stops the server.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
@@ -0,0 +1,160 @@
+// Package httpapi exposes the connector's process state over HTTP so it can
+// run under container orchestrators that expect health checks and a
+// Prometheus scrape target, instead of relying only on log output to detect
+// that syncs have silently stopped.
+package httpapi
+
+import (
+	"sync"
+	"time"
+)
+
+// Reading is the subset of a fetched glucose reading surfaced on /status and
+// used to compute glucose_reading_age_seconds.
+type Reading struct {
+	Value     float64   `json:"value"`
+	Unit      string    `json:"unit"`
+	Trend     string    `json:"trend"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// PatientStatus is one patient's slice of /status, keyed by the same patient
+// key used for the write-ahead buffer directory.
+type PatientStatus struct {
+	Reading      *Reading  `json:"reading,omitempty"`
+	LastPostedAt time.Time `json:"lastPostedAt,omitempty"`
+	LastError    string    `json:"lastError,omitempty"`
+}
+
+// Status is the connector's shared, concurrency-safe process state: the
+// counters backing /metrics and the fields backing /status and /readyz. The
+// main loop records into it after every login, reading, and Nightscout post;
+// the HTTP handlers only read it.
+type Status struct {
+	mu sync.RWMutex
+
+	readyMaxAge time.Duration
+
+	lastLoginAt  time.Time
+	lastLoginErr string
+
+	patients map[string]*PatientStatus
+
+	lastSyncDuration time.Duration
+
+	loginTotal    uint64
+	loginFailures uint64
+	postsTotal    uint64
+	postFailures  uint64
+}
+
+// NewStatus creates a Status whose /readyz considers the freshest successful
+// Nightscout post stale once it is older than readyMaxAge.
+func NewStatus(readyMaxAge time.Duration) *Status {
+	return &Status{
+		readyMaxAge: readyMaxAge,
+		patients:    make(map[string]*PatientStatus),
+	}
+}
+
+// RecordLogin records the outcome of a LibreLink Up login attempt.
+func (s *Status) RecordLogin(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.loginTotal++
+	s.lastLoginAt = time.Now()
+	if err != nil {
+		s.loginFailures++
+		s.lastLoginErr = err.Error()
+	} else {
+		s.lastLoginErr = ""
+	}
+}
+
+// RecordReading records the latest glucose reading fetched for a patient.
+func (s *Status) RecordReading(patient string, reading Reading) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := reading
+	s.patientLocked(patient).Reading = &r
+}
+
+// RecordPost records the outcome of posting one or more entries to
+// Nightscout for a patient.
+func (s *Status) RecordPost(patient string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.patientLocked(patient)
+	s.postsTotal++
+	if err != nil {
+		s.postFailures++
+		p.LastError = err.Error()
+		return
+	}
+	p.LastPostedAt = time.Now()
+	p.LastError = ""
+}
+
+// RecordSyncDuration records how long the most recent full sync tick took.
+func (s *Status) RecordSyncDuration(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastSyncDuration = d
+}
+
+// patientLocked returns (creating if necessary) the PatientStatus for slug.
+// Callers must hold s.mu.
+func (s *Status) patientLocked(slug string) *PatientStatus {
+	p, ok := s.patients[slug]
+	if !ok {
+		p = &PatientStatus{}
+		s.patients[slug] = p
+	}
+	return p
+}
+
+// freshestReadingAge returns the age of the most recent reading recorded
+// across all patients, and whether any reading has been recorded yet.
+// Callers must hold at least a read lock.
+func (s *Status) freshestReadingAge() (time.Duration, bool) {
+	var newest time.Time
+	found := false
+	for _, p := range s.patients {
+		if p.Reading == nil {
+			continue
+		}
+		if !found || p.Reading.Timestamp.After(newest) {
+			newest = p.Reading.Timestamp
+			found = true
+		}
+	}
+	if !found {
+		return 0, false
+	}
+	return time.Since(newest), true
+}
+
+// freshestPostAge returns the age of the most recent successful Nightscout
+// post recorded across all patients, and whether any post has succeeded
+// yet. Callers must hold at least a read lock.
+func (s *Status) freshestPostAge() (time.Duration, bool) {
+	var newest time.Time
+	found := false
+	for _, p := range s.patients {
+		if p.LastPostedAt.IsZero() {
+			continue
+		}
+		if !found || p.LastPostedAt.After(newest) {
+			newest = p.LastPostedAt
+			found = true
+		}
+	}
+	if !found {
+		return 0, false
+	}
+	return time.Since(newest), true
+}
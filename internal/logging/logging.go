@@ -0,0 +1,39 @@
+// Package logging provides the connector's shared slog.Logger setup: a
+// single LOG_LEVEL/LOG_FORMAT knob instead of permanent, hard-coded debug
+// prints, and helpers to redact sensitive values before they reach a log
+// line.
+package logging
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// New builds a slog.Logger configured from level (debug|info|warn|error,
+// defaulting to info) and format (text|json, defaulting to text).
+func New(level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: parseLevel(level)}
+
+	var handler slog.Handler
+	if strings.EqualFold(format, "json") {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	}
+
+	return slog.New(handler)
+}
+
+func parseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
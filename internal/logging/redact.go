@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// sensitiveFields lists JSON field and header names whose values must never
+// reach a log line verbatim.
+var sensitiveFields = map[string]bool{
+	"password":      true,
+	"authtoken":     true,
+	"token":         true,
+	"api-secret":    true,
+	"authorization": true,
+}
+
+const redacted = "***REDACTED***"
+
+// RedactJSON returns body with any sensitive field values masked, for safe
+// inclusion in debug logs. If body isn't a JSON object or array, it is
+// returned unchanged.
+func RedactJSON(body []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return string(body)
+	}
+
+	redactValue(v)
+
+	out, err := json.Marshal(v)
+	if err != nil {
+		return string(body)
+	}
+	return string(out)
+}
+
+func redactValue(v interface{}) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, nested := range val {
+			if sensitiveFields[strings.ToLower(key)] {
+				val[key] = redacted
+				continue
+			}
+			redactValue(nested)
+		}
+	case []interface{}:
+		for _, nested := range val {
+			redactValue(nested)
+		}
+	}
+}
+
+// RedactHeaders returns a copy of h with sensitive header values masked.
+func RedactHeaders(h http.Header) http.Header {
+	redactedHeaders := h.Clone()
+	for key := range redactedHeaders {
+		if sensitiveFields[strings.ToLower(key)] {
+			redactedHeaders.Set(key, redacted)
+		}
+	}
+	return redactedHeaders
+}
@@ -0,0 +1,132 @@
+// Package retry provides a shared retry-with-backoff helper for the HTTP
+// calls the connector makes against LibreLink Up and Nightscout, both of
+// which exhibit frequent short outages.
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultTimeout is the total wall-clock budget Do gives a call across all
+// attempts when the caller doesn't need a different value.
+const DefaultTimeout = 2 * time.Minute
+
+// Config controls Do's backoff shape and overall budget.
+type Config struct {
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the exponential backoff before jitter, so a single wait
+	// can run up to 20% above it. A server-specified Retry-After delay is not
+	// capped by it.
+	MaxBackoff time.Duration
+	// Timeout is the total wall-clock budget across all attempts; Do gives
+	// up once it elapses rather than retrying forever.
+	Timeout time.Duration
+}
+
+// DefaultConfig returns the package's default backoff shape - 500ms initial,
+// doubling, capped at 30s - with the given total timeout budget.
+func DefaultConfig(timeout time.Duration) Config {
+	return Config{
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		Timeout:        timeout,
+	}
+}
+
+// RetryableError marks an error as transient, optionally carrying a
+// server-specified delay (e.g. from a Retry-After header) to honor before
+// the next attempt.
+type RetryableError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryableError) Error() string { return e.Err.Error() }
+func (e *RetryableError) Unwrap() error { return e.Err }
+
+// WrapNetworkError marks a network-level failure (connection refused, DNS
+// failure, timeout, ...) as retryable.
+func WrapNetworkError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &RetryableError{Err: err}
+}
+
+// WrapHTTPStatus marks err as retryable when statusCode is 429 or 5xx,
+// honoring the Retry-After header (seconds or HTTP-date) when present. Any
+// other status code is returned unwrapped, i.e. treated as permanent.
+func WrapHTTPStatus(statusCode int, retryAfterHeader string, err error) error {
+	if statusCode != http.StatusTooManyRequests && statusCode < 500 {
+		return err
+	}
+	return &RetryableError{Err: err, RetryAfter: parseRetryAfter(retryAfterHeader)}
+}
+
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(header); err == nil {
+		return time.Until(when)
+	}
+	return 0
+}
+
+// Do calls fn, retrying while it returns a *RetryableError: backing off
+// exponentially with +/-20% jitter starting at cfg.InitialBackoff and
+// doubling up to cfg.MaxBackoff, until fn succeeds, returns a non-retryable
+// error, or cfg.Timeout has elapsed since the first attempt.
+func Do(cfg Config, fn func() error) error {
+	deadline := time.Now().Add(cfg.Timeout)
+	backoff := cfg.InitialBackoff
+	attempt := 0
+
+	for {
+		attempt++
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		var retryable *RetryableError
+		if !errors.As(err, &retryable) {
+			return err
+		}
+
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("gave up after %d attempts over %s: %w", attempt, cfg.Timeout, retryable.Err)
+		}
+
+		wait := backoff + jitter(backoff)
+		if retryable.RetryAfter > 0 {
+			wait = retryable.RetryAfter
+		}
+
+		if remaining := time.Until(deadline); wait > remaining {
+			wait = remaining
+		}
+		if wait > 0 {
+			time.Sleep(wait)
+		}
+
+		backoff *= 2
+		if backoff > cfg.MaxBackoff {
+			backoff = cfg.MaxBackoff
+		}
+	}
+}
+
+// jitter returns a random offset within +/-20% of d.
+func jitter(d time.Duration) time.Duration {
+	return time.Duration((rand.Float64()*0.4 - 0.2) * float64(d))
+}
@@ -0,0 +1,79 @@
+package retry
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDoRetriesUntilTimeoutThenGivesUp(t *testing.T) {
+	cfg := Config{InitialBackoff: 5 * time.Millisecond, MaxBackoff: 5 * time.Millisecond, Timeout: 30 * time.Millisecond}
+	attempts := 0
+	err := Do(cfg, func() error {
+		attempts++
+		return WrapNetworkError(errors.New("boom"))
+	})
+	if err == nil {
+		t.Fatal("Do() = nil, want an error once the timeout elapses")
+	}
+	if attempts < 2 {
+		t.Fatalf("attempts = %d, want at least 2 (one retry before the timeout)", attempts)
+	}
+}
+
+func TestDoSucceedsWithoutRetrying(t *testing.T) {
+	cfg := DefaultConfig(time.Second)
+	attempts := 0
+	err := Do(cfg, func() error {
+		attempts++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do() = %v, want nil", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("attempts = %d, want 1", attempts)
+	}
+}
+
+func TestDoStopsOnNonRetryableError(t *testing.T) {
+	cfg := DefaultConfig(time.Second)
+	attempts := 0
+	wantErr := errors.New("permanent")
+	err := Do(cfg, func() error {
+		attempts++
+		return wantErr
+	})
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("Do() = %v, want %v", err, wantErr)
+	}
+	if attempts != 1 {
+		t.Fatalf("attempts = %d, want 1 (non-retryable errors must not be retried)", attempts)
+	}
+}
+
+func TestWrapHTTPStatusHonorsRetryAfterSeconds(t *testing.T) {
+	err := WrapHTTPStatus(http.StatusTooManyRequests, "2", errors.New("rate limited"))
+
+	var retryable *RetryableError
+	if !errors.As(err, &retryable) {
+		t.Fatalf("WrapHTTPStatus(429, ...) = %v, want a *RetryableError", err)
+	}
+	if retryable.RetryAfter != 2*time.Second {
+		t.Fatalf("RetryAfter = %v, want 2s", retryable.RetryAfter)
+	}
+}
+
+func TestWrapHTTPStatusLeaves4xxNonRetryable(t *testing.T) {
+	base := errors.New("bad request")
+	err := WrapHTTPStatus(http.StatusBadRequest, "", base)
+
+	var retryable *RetryableError
+	if errors.As(err, &retryable) {
+		t.Fatalf("WrapHTTPStatus(400, ...) = %v, want a non-retryable error", err)
+	}
+	if !errors.Is(err, base) {
+		t.Fatalf("WrapHTTPStatus(400, ...) = %v, want it to unwrap to %v", err, base)
+	}
+}
@@ -7,15 +7,24 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/cookiejar"
+	"sort"
 	"time"
+
+	"github.com/MrCodeEU/homelab-automation/apps/nightscout-librelink-up-go/internal/logging"
+	"github.com/MrCodeEU/homelab-automation/apps/nightscout-librelink-up-go/internal/retry"
 )
 
 const (
 	appVersion  = "4.17.0"
 	appProduct  = "llu.ios"
 	contentType = "application/json"
+	// requestTimeout bounds a single HTTP attempt. It must stay well below
+	// retryTimeout so a slow or hanging attempt doesn't exhaust the whole
+	// retry budget before retry.Do gets a chance to back off and try again.
+	requestTimeout = 8 * time.Second
 )
 
 // Regional API endpoints for LibreLink Up
@@ -43,6 +52,10 @@ type Client struct {
 	authToken  string
 	accountID  string
 	httpClient *http.Client
+	logger     *slog.Logger
+	// retryTimeout is the total wall-clock budget Login, GetConnections, and
+	// GetReadings give a call across all retry attempts.
+	retryTimeout time.Duration
 }
 
 // Connection represents a LibreLink sensor connection
@@ -94,22 +107,33 @@ type connectionsResponse struct {
 	} `json:"data"`
 }
 
+type glucoseMeasurement struct {
+	Value          float64 `json:"Value"`
+	ValueInMgPerDl float64 `json:"ValueInMgPerDl"`
+	TrendArrow     int     `json:"TrendArrow"`
+	Timestamp      string  `json:"Timestamp"`
+}
+
 type glucoseResponse struct {
 	Status int `json:"status"`
 	Data   struct {
 		Connection struct {
-			GlucoseMeasurement struct {
-				Value          float64 `json:"Value"`
-				ValueInMgPerDl float64 `json:"ValueInMgPerDl"`
-				TrendArrow     int     `json:"TrendArrow"`
-				Timestamp      string  `json:"Timestamp"`
-			} `json:"glucoseMeasurement"`
+			GlucoseMeasurement glucoseMeasurement `json:"glucoseMeasurement"`
 		} `json:"connection"`
+		// GraphData holds the connection's recent history at the sensor's
+		// native ~1 minute cadence, used for historical backfill.
+		GraphData     []glucoseMeasurement `json:"graphData"`
+		ActiveSensors []struct {
+			Sensor struct {
+				DeviceID string `json:"deviceId"`
+				Serial   string `json:"sn"`
+			} `json:"sensor"`
+		} `json:"activeSensors"`
 	} `json:"data"`
 }
 
 // NewClient creates a new LibreLink Up client
-func NewClient(region, username, password string) (*Client, error) {
+func NewClient(region, username, password string, opts ...Option) (*Client, error) {
 	baseURL, ok := endpoints[region]
 	if !ok {
 		return nil, fmt.Errorf("unsupported region: %s", region)
@@ -121,18 +145,27 @@ func NewClient(region, username, password string) (*Client, error) {
 		return nil, fmt.Errorf("failed to create cookie jar: %w", err)
 	}
 
-	return &Client{
+	client := &Client{
 		baseURL:  baseURL,
 		username: username,
 		password: password,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: requestTimeout,
 			Jar:     jar,
 		},
-	}, nil
+		logger:       slog.Default(),
+		retryTimeout: retry.DefaultTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	return client, nil
 }
 
-// Login authenticates with LibreLink Up and retrieves auth token
+// Login authenticates with LibreLink Up and retrieves auth token. Network
+// errors and 429/5xx responses are retried with backoff up to c.retryTimeout.
 func (c *Client) Login() error {
 	loginReq := loginRequest{
 		Email:    c.username,
@@ -144,89 +177,94 @@ func (c *Client) Login() error {
 		return fmt.Errorf("failed to marshal login request: %w", err)
 	}
 
-	fmt.Printf("DEBUG: Attempting login to %s with email: %s\n", c.baseURL, c.username)
-	fmt.Printf("DEBUG: Request body: %s\n", string(reqBody))
-
-	req, err := http.NewRequest("POST", c.baseURL+"/llu/auth/login", bytes.NewBuffer(reqBody))
-	if err != nil {
-		return fmt.Errorf("failed to create login request: %w", err)
-	}
+	c.logger.Debug("attempting login", "url", c.baseURL, "email", c.username, "body", logging.RedactJSON(reqBody))
 
-	c.setHeaders(req)
+	var loginResp loginResponse
+	err = retry.Do(retry.DefaultConfig(c.retryTimeout), func() error {
+		req, err := http.NewRequest("POST", c.baseURL+"/llu/auth/login", bytes.NewBuffer(reqBody))
+		if err != nil {
+			return fmt.Errorf("failed to create login request: %w", err)
+		}
 
-	fmt.Println("DEBUG: Request headers:")
-	for k, v := range req.Header {
-		fmt.Printf("  %s: %s\n", k, v)
-	}
+		c.setHeaders(req)
+		c.logger.Debug("login request headers", "headers", logging.RedactHeaders(req.Header))
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("login request failed: %w", err)
-	}
-	defer resp.Body.Close()
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return retry.WrapNetworkError(fmt.Errorf("login request failed: %w", err))
+		}
+		defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
-	fmt.Printf("DEBUG: Response status: %d\n", resp.StatusCode)
-	fmt.Printf("DEBUG: Response body: %s\n", string(body))
+		body, _ := io.ReadAll(resp.Body)
+		c.logger.Debug("login response", "status", resp.StatusCode, "body", logging.RedactJSON(body))
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("login failed with HTTP status %d: %s", resp.StatusCode, string(body))
-	}
+		if resp.StatusCode != http.StatusOK {
+			httpErr := fmt.Errorf("login failed with HTTP status %d: %s", resp.StatusCode, string(body))
+			return retry.WrapHTTPStatus(resp.StatusCode, resp.Header.Get("Retry-After"), httpErr)
+		}
 
-	var loginResp loginResponse
-	if err := json.Unmarshal(body, &loginResp); err != nil {
-		return fmt.Errorf("failed to decode login response: %w", err)
+		if err := json.Unmarshal(body, &loginResp); err != nil {
+			return fmt.Errorf("failed to decode login response: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	if loginResp.Status != 0 {
-		fmt.Printf("DEBUG: Full login response: %+v\n", loginResp)
 		return fmt.Errorf("login failed with API status: %d", loginResp.Status)
 	}
 
 	c.authToken = loginResp.Data.AuthTicket.Token
 	c.accountID = loginResp.Data.User.ID
-	fmt.Printf("DEBUG: Successfully authenticated, token: %s...\n", c.authToken[:20])
-	fmt.Printf("DEBUG: Account ID: %s\n", c.accountID)
+	c.logger.Info("authenticated with LibreLink Up", "accountId", c.accountID)
 	return nil
 }
 
-// GetConnections retrieves all LibreLink connections (sensors)
+// GetConnections retrieves all LibreLink connections (sensors). Network
+// errors and 429/5xx responses are retried with backoff up to c.retryTimeout.
 func (c *Client) GetConnections() ([]Connection, error) {
 	if c.authToken == "" {
 		return nil, fmt.Errorf("not authenticated, call Login() first")
 	}
 
-	// Always try to fetch connections list
-	req, err := http.NewRequest("GET", c.baseURL+"/llu/connections", nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create connections request: %w", err)
-	}
+	var connResp connectionsResponse
+	err := retry.Do(retry.DefaultConfig(c.retryTimeout), func() error {
+		req, err := http.NewRequest("GET", c.baseURL+"/llu/connections", nil)
+		if err != nil {
+			return fmt.Errorf("failed to create connections request: %w", err)
+		}
 
-	c.setHeaders(req)
-	req.Header.Set("Authorization", "Bearer "+c.authToken)
+		c.setHeaders(req)
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("connections request failed: %w", err)
-	}
-	defer resp.Body.Close()
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return retry.WrapNetworkError(fmt.Errorf("connections request failed: %w", err))
+		}
+		defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
-	fmt.Printf("DEBUG: Connections response status: %d\n", resp.StatusCode)
-	fmt.Printf("DEBUG: Connections response body: %s\n", string(body))
+		body, _ := io.ReadAll(resp.Body)
+		c.logger.Debug("connections response", "status", resp.StatusCode, "body", logging.RedactJSON(body))
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("connections request failed with status %d: %s", resp.StatusCode, string(body))
-	}
+		if resp.StatusCode != http.StatusOK {
+			httpErr := fmt.Errorf("connections request failed with status %d: %s", resp.StatusCode, string(body))
+			return retry.WrapHTTPStatus(resp.StatusCode, resp.Header.Get("Retry-After"), httpErr)
+		}
 
-	var connResp connectionsResponse
-	if err := json.Unmarshal(body, &connResp); err != nil {
-		return nil, fmt.Errorf("failed to decode connections response: %w", err)
+		if err := json.Unmarshal(body, &connResp); err != nil {
+			return fmt.Errorf("failed to decode connections response: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// If no connections found, patient account - return account ID as connection
 	if len(connResp.Data) == 0 {
-		fmt.Printf("DEBUG: No connections found - using patient account ID as connection: %s\n", c.accountID)
+		c.logger.Debug("no connections found, using patient account as connection", "accountId", c.accountID)
 		return []Connection{
 			{
 				PatientID: c.accountID,
@@ -248,54 +286,99 @@ func (c *Client) GetConnections() ([]Connection, error) {
 	return connections, nil
 }
 
-// GetLatestReading retrieves the latest glucose reading for a patient
-func (c *Client) GetLatestReading(patientID string) (*GlucoseReading, error) {
-	if c.authToken == "" {
-		return nil, fmt.Errorf("not authenticated, call Login() first")
+// GetReadings retrieves the latest glucose reading for a patient along with
+// its recent history from the graph endpoint's sensor-native ~1 minute
+// cadence data, in a single request. Historical readings are filtered to
+// timestamps strictly after since, sorted oldest-first, and exclude any
+// point matching the latest reading's timestamp so callers can merge the two
+// without posting the same sample to Nightscout twice.
+func (c *Client) GetReadings(patientID string, since time.Time) (latest *GlucoseReading, historical []GlucoseReading, err error) {
+	glucoseResp, err := c.fetchGraph(patientID)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	url := fmt.Sprintf("%s/llu/connections/%s/graph", c.baseURL, patientID)
-	req, err := http.NewRequest("GET", url, nil)
+	measurement := glucoseResp.Data.Connection.GlucoseMeasurement
+	latestTimestamp, err := parseLibreLinkTimestamp(measurement.Timestamp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create glucose request: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse timestamp: %w", err)
+	}
+	latest = &GlucoseReading{
+		Value:      measurement.ValueInMgPerDl,
+		Unit:       "mg/dL",
+		Timestamp:  latestTimestamp,
+		TrendArrow: trendArrowToString(measurement.TrendArrow),
 	}
 
-	c.setHeaders(req)
-	req.Header.Set("Authorization", "Bearer "+c.authToken)
+	historical = make([]GlucoseReading, 0, len(glucoseResp.Data.GraphData))
+	for _, point := range glucoseResp.Data.GraphData {
+		timestamp, err := parseLibreLinkTimestamp(point.Timestamp)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse historical timestamp: %w", err)
+		}
+		if !timestamp.After(since) || timestamp.Equal(latestTimestamp) {
+			continue
+		}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("glucose request failed: %w", err)
+		historical = append(historical, GlucoseReading{
+			Value:      point.ValueInMgPerDl,
+			Unit:       "mg/dL",
+			Timestamp:  timestamp,
+			TrendArrow: trendArrowToString(point.TrendArrow),
+		})
 	}
-	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
-	fmt.Printf("DEBUG: Graph response status: %d\n", resp.StatusCode)
-	fmt.Printf("DEBUG: Graph response body: %s\n", string(body))
+	sort.Slice(historical, func(i, j int) bool {
+		return historical[i].Timestamp.Before(historical[j].Timestamp)
+	})
+
+	return latest, historical, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("glucose request failed with status %d: %s", resp.StatusCode, string(body))
+// fetchGraph calls the connection's graph endpoint, which backs both the
+// latest reading and the historical graph data. Network errors and 429/5xx
+// responses are retried with backoff up to c.retryTimeout.
+func (c *Client) fetchGraph(patientID string) (*glucoseResponse, error) {
+	if c.authToken == "" {
+		return nil, fmt.Errorf("not authenticated, call Login() first")
 	}
 
+	url := fmt.Sprintf("%s/llu/connections/%s/graph", c.baseURL, patientID)
+
 	var glucoseResp glucoseResponse
-	if err := json.Unmarshal(body, &glucoseResp); err != nil {
-		return nil, fmt.Errorf("failed to decode glucose response: %w", err)
-	}
+	err := retry.Do(retry.DefaultConfig(c.retryTimeout), func() error {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create glucose request: %w", err)
+		}
 
-	measurement := glucoseResp.Data.Connection.GlucoseMeasurement
+		c.setHeaders(req)
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return retry.WrapNetworkError(fmt.Errorf("glucose request failed: %w", err))
+		}
+		defer resp.Body.Close()
 
-	// Parse timestamp (format: "11/19/2024 3:14:29 PM")
-	timestamp, err := parseLibreLinkTimestamp(measurement.Timestamp)
+		body, _ := io.ReadAll(resp.Body)
+		c.logger.Debug("graph response", "status", resp.StatusCode, "body", logging.RedactJSON(body))
+
+		if resp.StatusCode != http.StatusOK {
+			httpErr := fmt.Errorf("glucose request failed with status %d: %s", resp.StatusCode, string(body))
+			return retry.WrapHTTPStatus(resp.StatusCode, resp.Header.Get("Retry-After"), httpErr)
+		}
+
+		if err := json.Unmarshal(body, &glucoseResp); err != nil {
+			return fmt.Errorf("failed to decode glucose response: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse timestamp: %w", err)
+		return nil, err
 	}
 
-	return &GlucoseReading{
-		Value:      measurement.ValueInMgPerDl,
-		Unit:       "mg/dL",
-		Timestamp:  timestamp,
-		TrendArrow: trendArrowToString(measurement.TrendArrow),
-	}, nil
+	return &glucoseResp, nil
 }
 
 func (c *Client) setHeaders(req *http.Request) {
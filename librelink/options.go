@@ -0,0 +1,26 @@
+package librelink
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Option configures optional Client behavior.
+type Option func(*Client)
+
+// WithLogger sets the logger used for request/response debug logging. If not
+// provided, NewClient falls back to slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithRetryTimeout sets the total wall-clock budget Login, GetConnections,
+// and GetReadings give a call across all retry attempts. If not provided,
+// NewClient falls back to retry.DefaultTimeout.
+func WithRetryTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.retryTimeout = d
+	}
+}
@@ -1,24 +1,38 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/MrCodeEU/homelab-automation/apps/nightscout-librelink-up-go/config"
+	"github.com/MrCodeEU/homelab-automation/apps/nightscout-librelink-up-go/internal/httpapi"
+	"github.com/MrCodeEU/homelab-automation/apps/nightscout-librelink-up-go/internal/logging"
 	"github.com/MrCodeEU/homelab-automation/apps/nightscout-librelink-up-go/librelink"
 	"github.com/MrCodeEU/homelab-automation/apps/nightscout-librelink-up-go/nightscout"
 	"github.com/joho/godotenv"
 )
 
+// maxConcurrentPatientSyncs bounds how many patients' readings are fetched
+// and posted at once, so a caregiver watching many LibreLinkUp shares
+// doesn't hammer LibreLink Up or Nightscout with unbounded concurrency.
+const maxConcurrentPatientSyncs = 4
+
 func main() {
 	// Load .env file if it exists (for local development)
 	_ = godotenv.Load()
 
-	log.Println("Starting Nightscout LibreLink Up Go Connector...")
-
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -30,17 +44,41 @@ func main() {
 		log.Fatalf("Invalid configuration: %v", err)
 	}
 
-	log.Printf("Configuration loaded - Region: %s, Interval: %d minutes, Nightscout URL: %s", 
-		cfg.LinkUpRegion, cfg.LinkUpTimeInterval, cfg.NightscoutURL)
+	logger := logging.New(cfg.LogLevel, cfg.LogFormat)
+	slog.SetDefault(logger)
+
+	logger.Info("starting Nightscout LibreLink Up Go Connector")
+	logger.Info("configuration loaded",
+		"region", cfg.LinkUpRegion, "intervalMinutes", cfg.LinkUpTimeInterval, "nightscoutUrl", cfg.NightscoutURL,
+		"httpRetryTimeout", cfg.HTTPRetryTimeout)
 
 	// Create LibreLink client
-	llClient, err := librelink.NewClient(cfg.LinkUpRegion, cfg.LinkUpUsername, cfg.LinkUpPassword)
+	llClient, err := librelink.NewClient(cfg.LinkUpRegion, cfg.LinkUpUsername, cfg.LinkUpPassword,
+		librelink.WithLogger(logger), librelink.WithRetryTimeout(cfg.HTTPRetryTimeout))
 	if err != nil {
 		log.Fatalf("Failed to create LibreLink client: %v", err)
 	}
 
-	// Create Nightscout client
-	nsClient := nightscout.NewClient(cfg.NightscoutURL, cfg.NightscoutAPIToken)
+	// Status backs /healthz, /readyz, /status, and /metrics: readiness
+	// requires a successful Nightscout post no older than twice the poll
+	// interval.
+	status := httpapi.NewStatus(2 * time.Duration(cfg.LinkUpTimeInterval) * time.Minute)
+	if cfg.HTTPListenAddr != "" {
+		server := httpapi.NewServer(cfg.HTTPListenAddr, status)
+		go func() {
+			if err := server.Serve(); err != nil {
+				logger.Error("http api server failed", "error", err)
+			}
+		}()
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := server.Shutdown(ctx); err != nil {
+				logger.Error("failed to shut down http api server", "error", err)
+			}
+		}()
+		logger.Info("http api server listening", "addr", cfg.HTTPListenAddr)
+	}
 
 	// Setup graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -51,29 +89,38 @@ func main() {
 	defer ticker.Stop()
 
 	// Initial run
-	if err := syncGlucoseData(llClient, nsClient); err != nil {
-		log.Printf("Error during initial sync: %v", err)
+	if err := syncGlucoseData(llClient, cfg, logger, status); err != nil {
+		logger.Error("error during initial sync", "error", err)
 	}
 
 	// Main loop
 	for {
 		select {
 		case <-ticker.C:
-			if err := syncGlucoseData(llClient, nsClient); err != nil {
-				log.Printf("Error syncing glucose data: %v", err)
+			if err := syncGlucoseData(llClient, cfg, logger, status); err != nil {
+				logger.Error("error syncing glucose data", "error", err)
 			}
 		case <-sigChan:
-			log.Println("Shutdown signal received, exiting gracefully...")
+			logger.Info("shutdown signal received, exiting gracefully")
 			return
 		}
 	}
 }
 
-func syncGlucoseData(llClient *librelink.Client, nsClient *nightscout.Client) error {
-	log.Println("Fetching glucose data from LibreLink Up...")
+// syncGlucoseData fetches every LibreLink connection and fans out a bounded
+// number of per-patient syncs in parallel, so one caregiver account watching
+// several LibreLinkUp shares can post each patient to a distinct Nightscout
+// target.
+func syncGlucoseData(llClient *librelink.Client, cfg *config.Config, logger *slog.Logger, status *httpapi.Status) error {
+	start := time.Now()
+	defer func() { status.RecordSyncDuration(time.Since(start)) }()
+
+	logger.Info("fetching glucose data from LibreLink Up")
 
 	// Authenticate with LibreLink
-	if err := llClient.Login(); err != nil {
+	err := llClient.Login()
+	status.RecordLogin(err)
+	if err != nil {
 		return err
 	}
 
@@ -84,34 +131,155 @@ func syncGlucoseData(llClient *librelink.Client, nsClient *nightscout.Client) er
 	}
 
 	if len(connections) == 0 {
-		log.Println("No active LibreLink connections found")
+		logger.Info("no active LibreLink connections found")
 		return nil
 	}
 
-	// Get latest glucose reading from first connection
-	reading, err := llClient.GetLatestReading(connections[0].PatientID)
+	multiPatient := len(connections) > 1
+
+	sem := make(chan struct{}, maxConcurrentPatientSyncs)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+
+	for _, connection := range connections {
+		connection := connection
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := syncPatient(llClient, cfg, logger, status, connection, multiPatient); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("patient %s: %w", connection.PatientID, err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// syncPatient backfills and posts one connection's readings to its resolved
+// Nightscout target.
+func syncPatient(llClient *librelink.Client, cfg *config.Config, logger *slog.Logger, status *httpapi.Status, connection librelink.Connection, multiPatient bool) error {
+	key := patientKey(connection)
+
+	target, err := cfg.ResolveNightscoutTarget(connection.PatientID, connection.FirstName, connection.LastName)
+	if err != nil {
+		return err
+	}
+
+	deviceSuffix := target.DeviceSuffix
+	if deviceSuffix == "" && multiPatient {
+		deviceSuffix = deviceSuffixFallback(connection)
+	}
+
+	bufferDir := cfg.NightscoutBufferDir
+	if bufferDir != "" {
+		bufferDir = filepath.Join(bufferDir, key)
+	}
+
+	nsClient, err := nightscout.NewClient(target.NightscoutURL, target.APISecret, bufferDir, deviceSuffix,
+		nightscout.WithLogger(logger), nightscout.WithRetryTimeout(cfg.HTTPRetryTimeout))
+	if err != nil {
+		return fmt.Errorf("failed to create Nightscout client: %w", err)
+	}
+
+	// Determine how far back to backfill: since the last entry we
+	// successfully posted, or a 12h fallback window on a fresh start.
+	since, err := nsClient.LastPostedTime()
+	if err != nil {
+		logger.Warn("failed to read last posted time, defaulting to 12h backfill window", "patientId", connection.PatientID, "error", err)
+	}
+	if since.IsZero() {
+		since = time.Now().Add(-12 * time.Hour)
+	}
+
+	reading, historical, err := llClient.GetReadings(connection.PatientID, since)
 	if err != nil {
 		return err
 	}
 
 	if reading == nil {
-		log.Println("No glucose reading available")
+		logger.Info("no glucose reading available", "patientId", connection.PatientID)
 		return nil
 	}
 
-	log.Printf("Glucose reading: %.1f %s (Trend: %s) at %s",
-		reading.Value, reading.Unit, reading.TrendArrow, reading.Timestamp.Format(time.RFC3339))
+	logger.Info("glucose reading",
+		"patientId", connection.PatientID, "value", reading.Value, "unit", reading.Unit,
+		"trend", reading.TrendArrow, "timestamp", reading.Timestamp.Format(time.RFC3339))
+
+	status.RecordReading(key, httpapi.Reading{
+		Value:     reading.Value,
+		Unit:      reading.Unit,
+		Trend:     reading.TrendArrow,
+		Timestamp: reading.Timestamp,
+	})
 
 	// Check if reading is recent (within last 15 minutes)
 	if time.Since(reading.Timestamp) > 15*time.Minute {
-		log.Printf("Warning: Reading is %v old, may be stale", time.Since(reading.Timestamp))
+		logger.Warn("reading may be stale", "patientId", connection.PatientID, "age", time.Since(reading.Timestamp))
+	}
+
+	readings := make([]*librelink.GlucoseReading, 0, len(historical)+1)
+	for i := range historical {
+		readings = append(readings, &historical[i])
 	}
+	readings = append(readings, reading)
 
-	// Post to Nightscout
-	if err := nsClient.PostGlucoseReading(reading); err != nil {
+	err = nsClient.PostGlucoseReadings(readings)
+	status.RecordPost(key, err)
+	if err != nil {
 		return err
 	}
 
-	log.Println("Successfully posted glucose data to Nightscout")
+	if len(historical) > 0 {
+		logger.Info("backfilled historical glucose readings", "patientId", connection.PatientID, "count", len(historical))
+	}
+
+	logger.Info("successfully posted glucose data to Nightscout", "patientId", connection.PatientID)
 	return nil
 }
+
+// patientKey returns the filesystem-safe identifier used to partition a
+// connection's write-ahead buffer directory and status entry. It's derived
+// from PatientID rather than the connection's name: two distinct
+// LibreLinkUp connections can share a display name (twins, test accounts, a
+// caregiver watching siblings), and keying shared state on anything but the
+// (always unique) PatientID would let one patient's buffered entries get
+// drained and posted to a different patient's Nightscout target.
+func patientKey(connection librelink.Connection) string {
+	return sanitizePathSegment(connection.PatientID)
+}
+
+// deviceSuffixFallback derives a human-readable Nightscout device suffix
+// from a connection's name, used when multiPatient is true and the operator
+// hasn't configured an explicit DeviceSuffix. Unlike patientKey, this value
+// is only ever embedded in the Device field posted to Nightscout, never
+// used as a filesystem path, so it doesn't need to be unique or sanitized.
+func deviceSuffixFallback(connection librelink.Connection) string {
+	name := strings.TrimSpace(connection.FirstName + " " + connection.LastName)
+	if name == "" {
+		return connection.PatientID
+	}
+	return strings.ToLower(strings.ReplaceAll(name, " ", "-"))
+}
+
+// sanitizePathSegment makes s safe to use as a single path segment joined
+// onto a trusted base directory. LibreLinkUp connection fields come from a
+// caregiver share accepted from a third party, not trusted input, so a
+// crafted PatientID like "../../../etc" must not be able to escape the
+// intended directory: path separators are replaced, and a result that would
+// resolve to "." or ".." falls back to a hash of the original value.
+func sanitizePathSegment(s string) string {
+	replacer := strings.NewReplacer("/", "-", "\\", "-")
+	cleaned := replacer.Replace(s)
+	if cleaned == "" || cleaned == "." || cleaned == ".." {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+	return cleaned
+}
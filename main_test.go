@@ -0,0 +1,56 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MrCodeEU/homelab-automation/apps/nightscout-librelink-up-go/librelink"
+)
+
+func TestPatientKeyUsesPatientIDNotName(t *testing.T) {
+	twinA := librelink.Connection{PatientID: "patient-a", FirstName: "Alex", LastName: "Doe"}
+	twinB := librelink.Connection{PatientID: "patient-b", FirstName: "Alex", LastName: "Doe"}
+
+	if patientKey(twinA) == patientKey(twinB) {
+		t.Fatalf("patientKey collided for two connections sharing a name: %q", patientKey(twinA))
+	}
+	if got := patientKey(twinA); got != "patient-a" {
+		t.Fatalf("patientKey() = %q, want %q", got, "patient-a")
+	}
+}
+
+func TestSanitizePathSegmentNeutralizesTraversal(t *testing.T) {
+	malicious := "../../../../tmp"
+
+	got := sanitizePathSegment(malicious)
+	if strings.ContainsAny(got, "/\\") {
+		t.Fatalf("sanitizePathSegment(%q) = %q, still contains a path separator", malicious, got)
+	}
+
+	joined := filepath.Join("data/nightscout-wal", got)
+	if !strings.HasPrefix(joined, "data/nightscout-wal"+string(filepath.Separator)) {
+		t.Fatalf("filepath.Join with sanitized segment escaped the buffer dir: %q", joined)
+	}
+}
+
+func TestSanitizePathSegmentHashesDotSegments(t *testing.T) {
+	for _, s := range []string{".", ".."} {
+		got := sanitizePathSegment(s)
+		if got == "." || got == ".." || got == "" {
+			t.Fatalf("sanitizePathSegment(%q) = %q, want a non-traversal fallback", s, got)
+		}
+	}
+}
+
+func TestDeviceSuffixFallbackUsesName(t *testing.T) {
+	conn := librelink.Connection{PatientID: "abc123", FirstName: "Jane", LastName: "Doe"}
+	if got, want := deviceSuffixFallback(conn), "jane-doe"; got != want {
+		t.Fatalf("deviceSuffixFallback() = %q, want %q", got, want)
+	}
+
+	anon := librelink.Connection{PatientID: "abc123"}
+	if got, want := deviceSuffixFallback(anon), "abc123"; got != want {
+		t.Fatalf("deviceSuffixFallback() with no name = %q, want %q", got, want)
+	}
+}
@@ -5,19 +5,40 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/MrCodeEU/homelab-automation/apps/nightscout-librelink-up-go/internal/logging"
+	"github.com/MrCodeEU/homelab-automation/apps/nightscout-librelink-up-go/internal/retry"
 	"github.com/MrCodeEU/homelab-automation/apps/nightscout-librelink-up-go/librelink"
 )
 
+// lastPostedFileName stores the timestamp of the newest entry this client has
+// successfully posted to Nightscout, so a freshly-started container knows
+// how far back it needs to backfill history.
+const lastPostedFileName = "last-posted.json"
+
+// requestTimeout bounds a single HTTP attempt. It must stay well below
+// retryTimeout so a slow or hanging attempt doesn't exhaust the whole retry
+// budget before retry.Do gets a chance to back off and try again.
+const requestTimeout = 8 * time.Second
+
 // Client represents a Nightscout API client
 type Client struct {
-	baseURL    string
-	apiToken   string
-	httpClient *http.Client
+	baseURL      string
+	apiToken     string
+	httpClient   *http.Client
+	wal          *wal
+	bufferDir    string
+	deviceSuffix string
+	logger       *slog.Logger
+	// retryTimeout is the total wall-clock budget postEntries gives a POST
+	// across all retry attempts.
+	retryTimeout time.Duration
 }
 
 // Entry represents a Nightscout glucose entry
@@ -30,36 +51,186 @@ type Entry struct {
 	DateString string  `json:"dateString"`
 }
 
-// NewClient creates a new Nightscout API client
-func NewClient(baseURL, apiToken string) *Client {
-	return &Client{
+// NewClient creates a new Nightscout API client. bufferDir is the directory
+// used to persist entries that fail to post so they can be replayed once
+// Nightscout (or the network) recovers; pass an empty string to disable
+// buffering entirely. deviceSuffix is appended to the posted Entry.Device as
+// "nightscout-librelink-up-go/<deviceSuffix>", letting multiple patients
+// share one Nightscout instance without clobbering each other's device
+// field; pass an empty string to use the bare device name.
+func NewClient(baseURL, apiToken, bufferDir, deviceSuffix string, opts ...Option) (*Client, error) {
+	client := &Client{
 		baseURL:  baseURL,
 		apiToken: apiToken,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: requestTimeout,
 		},
+		bufferDir:    bufferDir,
+		deviceSuffix: deviceSuffix,
+		logger:       slog.Default(),
+		retryTimeout: retry.DefaultTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	if bufferDir != "" {
+		w, err := newWAL(bufferDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize write-ahead buffer: %w", err)
+		}
+		client.wal = w
+	}
+
+	return client, nil
+}
+
+// PostGlucoseReadings posts a batch of glucose readings to Nightscout as a
+// single POST array, draining any previously buffered entries first. This is
+// used to backfill historical readings alongside the latest one. If the
+// batch fails to post, every reading in it is appended to the write-ahead
+// buffer instead of being dropped.
+func (c *Client) PostGlucoseReadings(readings []*librelink.GlucoseReading) error {
+	if len(readings) == 0 {
+		return nil
+	}
+
+	c.drainBuffer()
+
+	entries := make([]Entry, len(readings))
+	newest := readings[0].Timestamp
+	for i, reading := range readings {
+		entries[i] = c.readingToEntry(reading)
+		if reading.Timestamp.After(newest) {
+			newest = reading.Timestamp
+		}
+	}
+
+	if err := c.postEntries(entries); err != nil {
+		if c.wal != nil {
+			if bufErr := c.wal.AppendAll(entries); bufErr != nil {
+				c.logger.Error("failed to buffer glucose entries after post failure", "error", bufErr)
+			}
+		}
+		return err
+	}
+
+	c.setLastPostedTime(newest)
+	return nil
+}
+
+// drainBuffer replays any buffered entries in FIFO order as a single batched
+// POST, compacting the write-ahead log once they've been accepted. A failure
+// here is logged and left for the next sync tick to retry.
+func (c *Client) drainBuffer() {
+	if c.wal == nil {
+		return
+	}
+
+	queued, err := c.wal.Drain()
+	if err != nil {
+		c.logger.Error("failed to read write-ahead buffer", "error", err)
+		return
+	}
+	if len(queued) == 0 {
+		return
+	}
+
+	if err := c.postEntries(queued); err != nil {
+		c.logger.Warn("failed to replay buffered entries, will retry next sync", "count", len(queued), "error", err)
+		return
+	}
+
+	if err := c.wal.Compact(nil); err != nil {
+		c.logger.Error("failed to compact write-ahead buffer", "error", err)
+		return
+	}
+
+	newest := time.UnixMilli(queued[0].Date)
+	for _, entry := range queued {
+		if t := time.UnixMilli(entry.Date); t.After(newest) {
+			newest = t
+		}
 	}
+	c.setLastPostedTime(newest)
+
+	c.logger.Info("backfilled buffered glucose entries to Nightscout", "count", len(queued))
 }
 
-// PostGlucoseReading posts a glucose reading to Nightscout
-func (c *Client) PostGlucoseReading(reading *librelink.GlucoseReading) error {
-	if reading == nil {
-		return fmt.Errorf("reading is nil")
+// LastPostedTime returns the timestamp of the newest entry this client has
+// successfully posted, or the zero time if nothing has been persisted yet
+// (buffering disabled, or this is the first run).
+func (c *Client) LastPostedTime() (time.Time, error) {
+	if c.bufferDir == "" {
+		return time.Time{}, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(c.bufferDir, lastPostedFileName))
+	if os.IsNotExist(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read last-posted marker: %w", err)
+	}
+
+	var state struct {
+		Timestamp time.Time `json:"timestamp"`
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return time.Time{}, fmt.Errorf("failed to decode last-posted marker: %w", err)
+	}
+
+	return state.Timestamp, nil
+}
+
+// setLastPostedTime persists t as the newest successfully-posted entry
+// timestamp. Failures are logged rather than returned since this is a
+// best-effort optimization, not something a sync tick should fail over.
+func (c *Client) setLastPostedTime(t time.Time) {
+	if c.bufferDir == "" {
+		return
+	}
+	if current, err := c.LastPostedTime(); err == nil && !t.After(current) {
+		return
+	}
+
+	data, err := json.Marshal(struct {
+		Timestamp time.Time `json:"timestamp"`
+	}{Timestamp: t})
+	if err != nil {
+		c.logger.Error("failed to encode last-posted marker", "error", err)
+		return
 	}
 
-	entry := Entry{
+	if err := os.WriteFile(filepath.Join(c.bufferDir, lastPostedFileName), data, 0o644); err != nil {
+		c.logger.Error("failed to persist last-posted marker", "error", err)
+	}
+}
+
+func (c *Client) readingToEntry(reading *librelink.GlucoseReading) Entry {
+	device := "nightscout-librelink-up-go"
+	if c.deviceSuffix != "" {
+		device = device + "/" + c.deviceSuffix
+	}
+
+	return Entry{
 		Type:       "sgv",
 		SGV:        reading.Value,
 		Direction:  convertTrendArrow(reading.TrendArrow),
-		Device:     "nightscout-librelink-up-go",
+		Device:     device,
 		Date:       reading.Timestamp.UnixMilli(),
 		DateString: reading.Timestamp.Format(time.RFC3339),
 	}
+}
 
-	entries := []Entry{entry}
+// postEntries POSTs a batch of entries to Nightscout's /api/v1/entries
+// endpoint, which accepts a JSON array. Network errors and 429/5xx responses
+// are retried with backoff up to c.retryTimeout.
+func (c *Client) postEntries(entries []Entry) error {
 	reqBody, err := json.Marshal(entries)
 	if err != nil {
-		return fmt.Errorf("failed to marshal entry: %w", err)
+		return fmt.Errorf("failed to marshal entries: %w", err)
 	}
 
 	// Add http:// or https:// if not present
@@ -75,29 +246,34 @@ func (c *Client) PostGlucoseReading(reading *librelink.GlucoseReading) error {
 	}
 
 	url := fmt.Sprintf("%s/api/v1/entries", baseURL)
-	log.Printf("Posting glucose data to: %s", url)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
+	c.logger.Debug("posting glucose entries", "count", len(entries), "url", url)
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("api-secret", c.apiToken)
-	// Trick Nightscout into thinking this is a secure connection to avoid redirects
-	req.Header.Set("X-Forwarded-Proto", "https")
+	return retry.Do(retry.DefaultConfig(c.retryTimeout), func() error {
+		req, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
-	}
-	defer resp.Body.Close()
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("api-secret", c.apiToken)
+		// Trick Nightscout into thinking this is a secure connection to avoid redirects
+		req.Header.Set("X-Forwarded-Proto", "https")
+		c.logger.Debug("entries request headers", "headers", logging.RedactHeaders(req.Header))
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
-	}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return retry.WrapNetworkError(fmt.Errorf("request failed: %w", err))
+		}
+		defer resp.Body.Close()
 
-	return nil
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			body, _ := io.ReadAll(resp.Body)
+			httpErr := fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
+			return retry.WrapHTTPStatus(resp.StatusCode, resp.Header.Get("Retry-After"), httpErr)
+		}
+
+		return nil
+	})
 }
 
 // convertTrendArrow converts LibreLink trend arrows to Nightscout direction format
@@ -0,0 +1,145 @@
+package nightscout
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// maxWALEntries caps how many queued entries the write-ahead log keeps on
+// disk. Once exceeded, the oldest entries are dropped to make room for new
+// ones rather than growing the file unbounded while Nightscout is down.
+const maxWALEntries = 2016 // ~1 week of readings at a 5 minute interval
+
+const walFileName = "wal.jsonl"
+
+// wal is a simple newline-delimited JSON buffer of entries that failed to
+// post to Nightscout, rewritten whole through a temp file and rename on every
+// change. It lets the connector survive transient
+// network or Nightscout outages without dropping readings, mirroring the
+// durable-buffer pattern used by remote storage adapters that decouple
+// producers from a possibly-unavailable long-term store.
+type wal struct {
+	path string
+	mu   sync.Mutex
+}
+
+// newWAL creates (or reuses) a write-ahead log rooted at dir.
+func newWAL(dir string) (*wal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create buffer directory: %w", err)
+	}
+	return &wal{path: filepath.Join(dir, walFileName)}, nil
+}
+
+// Append queues entry for later replay, dropping the oldest queued entry if
+// the log is already at capacity.
+func (w *wal) Append(entry Entry) error {
+	return w.AppendAll([]Entry{entry})
+}
+
+// AppendAll queues every entry in entries for later replay in a single
+// read-modify-write pass, dropping the oldest queued entries if the combined
+// result exceeds capacity. Use this instead of calling Append in a loop when
+// buffering a failed batch post, which would otherwise re-read and rewrite
+// the whole log once per entry.
+func (w *wal) AppendAll(entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	queued, err := w.readLocked()
+	if err != nil {
+		return err
+	}
+
+	queued = append(queued, entries...)
+	if len(queued) > maxWALEntries {
+		queued = queued[len(queued)-maxWALEntries:]
+	}
+
+	return w.writeLocked(queued)
+}
+
+// Drain returns every queued entry in FIFO order without removing them from
+// disk; call Compact once they have been successfully replayed.
+func (w *wal) Drain() ([]Entry, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.readLocked()
+}
+
+// Compact rewrites the log to contain only leftover, truncating away
+// everything that was successfully drained.
+func (w *wal) Compact(leftover []Entry) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writeLocked(leftover)
+}
+
+func (w *wal) readLocked() ([]Entry, error) {
+	f, err := os.Open(w.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WAL: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode buffered entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read WAL: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (w *wal) writeLocked(entries []Entry) error {
+	tmpPath := w.path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open WAL for writing: %w", err)
+	}
+
+	writer := bufio.NewWriter(f)
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("failed to encode buffered entry: %w", err)
+		}
+		if _, err := writer.Write(append(line, '\n')); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write buffered entry: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to flush WAL: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close WAL: %w", err)
+	}
+
+	return os.Rename(tmpPath, w.path)
+}
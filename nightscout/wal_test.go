@@ -0,0 +1,96 @@
+package nightscout
+
+import "testing"
+
+func TestWALAppendEvictsOldestBeyondCapacity(t *testing.T) {
+	w, err := newWAL(t.TempDir())
+	if err != nil {
+		t.Fatalf("newWAL() failed: %v", err)
+	}
+
+	full := make([]Entry, maxWALEntries)
+	for i := range full {
+		full[i] = Entry{Date: int64(i)}
+	}
+	if err := w.AppendAll(full); err != nil {
+		t.Fatalf("AppendAll() failed: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := w.Append(Entry{Date: int64(maxWALEntries + i)}); err != nil {
+			t.Fatalf("Append(%d) failed: %v", i, err)
+		}
+	}
+
+	queued, err := w.Drain()
+	if err != nil {
+		t.Fatalf("Drain() failed: %v", err)
+	}
+	if len(queued) != maxWALEntries {
+		t.Fatalf("len(queued) = %d, want %d", len(queued), maxWALEntries)
+	}
+	if queued[0].Date != 5 {
+		t.Fatalf("queued[0].Date = %d, want 5 (the first 5 entries should have been evicted)", queued[0].Date)
+	}
+	if last := queued[len(queued)-1].Date; last != int64(maxWALEntries+4) {
+		t.Fatalf("queued[last].Date = %d, want %d", last, maxWALEntries+4)
+	}
+}
+
+func TestWALDrainAndCompactRoundtrip(t *testing.T) {
+	w, err := newWAL(t.TempDir())
+	if err != nil {
+		t.Fatalf("newWAL() failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := w.Append(Entry{Date: int64(i)}); err != nil {
+			t.Fatalf("Append(%d) failed: %v", i, err)
+		}
+	}
+
+	queued, err := w.Drain()
+	if err != nil {
+		t.Fatalf("Drain() failed: %v", err)
+	}
+	if len(queued) != 3 {
+		t.Fatalf("len(queued) = %d, want 3", len(queued))
+	}
+
+	if err := w.Compact(nil); err != nil {
+		t.Fatalf("Compact(nil) failed: %v", err)
+	}
+
+	remaining, err := w.Drain()
+	if err != nil {
+		t.Fatalf("Drain() after Compact(nil) failed: %v", err)
+	}
+	if len(remaining) != 0 {
+		t.Fatalf("len(remaining) = %d, want 0 after compacting away everything drained", len(remaining))
+	}
+}
+
+func TestWALCompactKeepsLeftover(t *testing.T) {
+	w, err := newWAL(t.TempDir())
+	if err != nil {
+		t.Fatalf("newWAL() failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := w.Append(Entry{Date: int64(i)}); err != nil {
+			t.Fatalf("Append(%d) failed: %v", i, err)
+		}
+	}
+
+	if err := w.Compact([]Entry{{Date: 1}}); err != nil {
+		t.Fatalf("Compact() failed: %v", err)
+	}
+
+	remaining, err := w.Drain()
+	if err != nil {
+		t.Fatalf("Drain() after Compact() failed: %v", err)
+	}
+	if len(remaining) != 1 || remaining[0].Date != 1 {
+		t.Fatalf("remaining = %+v, want [{Date:1}]", remaining)
+	}
+}